@@ -1,18 +1,24 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"os/user"
 	"path/filepath"
 	"regexp"
+	"strings"
+	"syscall"
 	"text/tabwriter"
 	"time"
 
 	"github.com/DTreshy/sup/internal/command"
+	"github.com/DTreshy/sup/internal/discovery"
 	"github.com/DTreshy/sup/internal/envs"
 	"github.com/DTreshy/sup/internal/flags"
+	"github.com/DTreshy/sup/internal/log"
 	"github.com/DTreshy/sup/internal/network"
 	"github.com/DTreshy/sup/internal/sup"
 	"github.com/DTreshy/sup/internal/supfile"
@@ -26,6 +32,7 @@ var (
 	ErrCmd              = errors.New("Unknown command/target")
 	ErrTargetNoCommands = errors.New("No commands defined for a given target")
 	ErrConfigFile       = errors.New("Unknown ssh_config file")
+	ErrNoDiscovery      = errors.New("network has no discovery config to respond for")
 
 	flag *flags.Flags
 )
@@ -80,6 +87,18 @@ func parseArgs(conf *supfile.Supfile) (*network.Network, []*command.Command, err
 
 	net.Hosts = append(net.Hosts, hosts...)
 
+	// Discover additional hosts (e.g. via mDNS or UDP broadcast) before the
+	// --only/--except filters run, so discovered hosts can be combined with
+	// static entries and filtered the same way.
+	if net.Discovery != nil {
+		discovered, warnings := discovery.Discover(*net.Discovery, discovery.MDNS{}, discovery.Broadcast{Self: args[0]})
+		for _, warning := range warnings {
+			fmt.Fprintln(os.Stderr, warning)
+		}
+
+		net.Hosts = append(net.Hosts, discovered...)
+	}
+
 	// Does the <network> have at least one host?
 	if len(net.Hosts) == 0 {
 		networkUsage(conf)
@@ -146,6 +165,28 @@ func parseArgs(conf *supfile.Supfile) (*network.Network, []*command.Command, err
 	return &net, commands, nil
 }
 
+// respondToDiscovery runs the UDP broadcast responder for net's discovery
+// config until interrupted, so this host, run with --discover-respond,
+// answers solicitations from other sup instances. It's the listening half of
+// discovery.Broadcast: without some host running this, Broadcast.Discover
+// never hears anything back.
+func respondToDiscovery(netName string, cfg *discovery.Config) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	trap := make(chan os.Signal, 1)
+	signal.Notify(trap, os.Interrupt, syscall.SIGTERM)
+
+	defer signal.Stop(trap)
+
+	go func() {
+		<-trap
+		cancel()
+	}()
+
+	return discovery.Broadcast{Self: netName}.Respond(ctx, *cfg)
+}
+
 func resolvePath(path string) string {
 	if path == "" {
 		return ""
@@ -198,6 +239,37 @@ func main() {
 		os.Exit(1)
 	}
 
+	// --discover-respond makes this host answer other instances' discovery
+	// broadcasts instead of running any command; it only needs a network
+	// name, not a full command line, so it's handled before parseArgs.
+	if flag.DiscoverRespond {
+		args := flags.Args()
+		if len(args) < 1 {
+			networkUsage(conf)
+			fmt.Fprintln(os.Stderr, ErrUsage)
+			os.Exit(1)
+		}
+
+		respondNet, ok := conf.Networks.Get(args[0])
+		if !ok {
+			networkUsage(conf)
+			fmt.Fprintln(os.Stderr, ErrUnknownNetwork)
+			os.Exit(1)
+		}
+
+		if respondNet.Discovery == nil {
+			fmt.Fprintln(os.Stderr, ErrNoDiscovery)
+			os.Exit(1)
+		}
+
+		if err := respondToDiscovery(args[0], respondNet.Discovery); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
 	// Parse network and commands to be run from args.
 	net, commands, err := parseArgs(conf)
 	if err != nil {
@@ -278,6 +350,12 @@ func main() {
 				net.User = conf.User
 				net.IdentityFile = resolvePath(conf.IdentityFile)
 				net.Hosts = []string{fmt.Sprintf("%s:%d", conf.HostName, conf.Port)}
+
+				// Mirror ssh_config's ProxyJump so users don't have to
+				// duplicate bastion topology in the Supfile.
+				if conf.ProxyJump != "" {
+					net.Bastions = strings.Split(conf.ProxyJump, ",")
+				}
 			}
 		}
 	}
@@ -300,13 +378,35 @@ func main() {
 		os.Exit(1)
 	}
 
+	logLevel, err := log.ParseLevel(flag.LogLevel)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	logFormat, err := log.ParseFormat(flag.LogFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	app.Logger(log.New(os.Stderr, logLevel, logFormat))
 	app.Debug(flag.Debug)
 	app.Prefix(!flag.DisablePrefix)
+	app.TTY(flag.TTY)
+	app.TTYForce(flag.TTYForce)
+	app.Grace(flag.Grace)
 
 	// Run all the commands in the given network.
 	err = app.Run(net, vars, commands...)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
+
+		var runErrs *sup.RunErrors
+		if errors.As(err, &runErrs) && runErrs.ExitCode != 0 {
+			os.Exit(runErrs.ExitCode)
+		}
+
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,174 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBroadcastPort is used when Config.Port is unset.
+const DefaultBroadcastPort = 9999
+
+// solicitationPrefix marks our own broadcast packets so replies from other
+// software sharing the port are ignored.
+const solicitationPrefix = "sup:"
+
+// Broadcast discovers hosts by emitting a UDP broadcast solicitation and
+// collecting replies from anyone running Respond on the same namespace.
+// It's the fallback for networks where mDNS is blocked or unavailable.
+type Broadcast struct {
+	// Self identifies this node in the solicitation so a host can choose to
+	// ignore its own broadcast if it ever receives it back.
+	Self string
+}
+
+func (Broadcast) Name() string { return "broadcast" }
+
+func (b Broadcast) Discover(cfg Config) ([]string, error) {
+	port := cfg.Port
+	if port == 0 {
+		port = DefaultBroadcastPort
+	}
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{Port: port})
+	if err != nil {
+		return nil, errors.Join(err, errors.New("opening broadcast socket failed"))
+	}
+	defer conn.Close()
+
+	solicitation := fmt.Sprintf("%s%s:%s", solicitationPrefix, cfg.Namespace, b.Self)
+
+	broadcastAddr := &net.UDPAddr{IP: net.IPv4bcast, Port: port}
+	if _, err := conn.WriteToUDP([]byte(solicitation), broadcastAddr); err != nil {
+		return nil, errors.Join(err, errors.New("sending broadcast solicitation failed"))
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(cfg.timeout())); err != nil {
+		return nil, errors.Join(err, errors.New("setting broadcast read deadline failed"))
+	}
+
+	seen := make(map[string]bool)
+
+	// The broadcast is looped back to our own socket too, so anything sent
+	// from one of this host's own addresses is dropped.
+	local := make(map[string]bool)
+
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, a := range addrs {
+			if ipNet, ok := a.(*net.IPNet); ok {
+				local[ipNet.IP.String()] = true
+			}
+		}
+	}
+
+	var hosts []string
+
+	buf := make([]byte, 1024)
+
+	for {
+		n, addr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			// Read timeout ends the collection window; that's expected.
+			break
+		}
+
+		if local[addr.IP.String()] {
+			continue
+		}
+
+		host, ok := parseReply(buf[:n], cfg.Namespace, addr, port)
+		if !ok || seen[host] {
+			continue
+		}
+
+		seen[host] = true
+
+		hosts = append(hosts, host)
+	}
+
+	return hosts, nil
+}
+
+// parseReply validates a reply as "sup:<namespace>:<responder>" and turns
+// the sender's address into a dial-able "host:port" string.
+func parseReply(packet []byte, namespace string, from *net.UDPAddr, port int) (string, bool) {
+	reply := string(packet)
+	if !strings.HasPrefix(reply, solicitationPrefix) {
+		return "", false
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(reply, solicitationPrefix), ":", 2)
+	if len(parts) != 2 || parts[0] != namespace {
+		return "", false
+	}
+
+	return net.JoinHostPort(from.IP.String(), strconv.Itoa(port)), true
+}
+
+// Respond is the other half of the broadcast protocol: it listens for
+// "sup:<namespace>:<sender>" solicitations and answers each one straight
+// back to the sender with our own identity, so a Discover call elsewhere
+// on the LAN has something to hear back from. It's meant to be run
+// long-lived on any host that should be discoverable, and blocks until ctx
+// is cancelled.
+func (b Broadcast) Respond(ctx context.Context, cfg Config) error {
+	port := cfg.Port
+	if port == 0 {
+		port = DefaultBroadcastPort
+	}
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{Port: port})
+	if err != nil {
+		return errors.Join(err, errors.New("opening broadcast socket failed"))
+	}
+	defer conn.Close()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	reply := fmt.Sprintf("%s%s:%s", solicitationPrefix, cfg.Namespace, b.Self)
+
+	buf := make([]byte, 1024)
+
+	for {
+		n, addr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			return errors.Join(err, errors.New("reading broadcast solicitation failed"))
+		}
+
+		namespace, ok := parseSolicitation(buf[:n])
+		if !ok || namespace != cfg.Namespace {
+			continue
+		}
+
+		if _, err := conn.WriteToUDP([]byte(reply), addr); err != nil {
+			return errors.Join(err, errors.New("sending broadcast reply failed"))
+		}
+	}
+}
+
+// parseSolicitation extracts the namespace from a "sup:<namespace>:<sender>"
+// packet, the same format both the solicitation and the reply use.
+func parseSolicitation(packet []byte) (string, bool) {
+	msg := string(packet)
+	if !strings.HasPrefix(msg, solicitationPrefix) {
+		return "", false
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(msg, solicitationPrefix), ":", 2)
+	if len(parts) != 2 {
+		return "", false
+	}
+
+	return parts[0], true
+}
@@ -0,0 +1,68 @@
+package discovery
+
+import (
+	"net"
+	"testing"
+)
+
+func TestParseReply(t *testing.T) {
+	from := &net.UDPAddr{IP: net.ParseIP("192.0.2.1")}
+
+	tests := []struct {
+		name      string
+		packet    string
+		namespace string
+		wantHost  string
+		wantOK    bool
+	}{
+		{"valid reply", "sup:prod:web1", "prod", "192.0.2.1:22", true},
+		{"namespace mismatch", "sup:staging:web1", "prod", "", false},
+		{"missing prefix", "nope:prod:web1", "prod", "", false},
+		{"missing responder part", "sup:prod", "prod", "", false},
+		{"empty packet", "", "prod", "", false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			host, ok := parseReply([]byte(tt.packet), tt.namespace, from, 22)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+
+			if host != tt.wantHost {
+				t.Fatalf("host = %q, want %q", host, tt.wantHost)
+			}
+		})
+	}
+}
+
+func TestParseSolicitation(t *testing.T) {
+	tests := []struct {
+		name          string
+		packet        string
+		wantNamespace string
+		wantOK        bool
+	}{
+		{"valid solicitation", "sup:prod:ctrl", "prod", true},
+		{"missing prefix", "nope:prod:ctrl", "", false},
+		{"missing sender part", "sup:prod", "", false},
+		{"empty packet", "", "", false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			namespace, ok := parseSolicitation([]byte(tt.packet))
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+
+			if namespace != tt.wantNamespace {
+				t.Fatalf("namespace = %q, want %q", namespace, tt.wantNamespace)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Package discovery populates a network's host list at runtime by asking
+// pluggable providers (mDNS/DNS-SD, UDP broadcast, ...) who is out there,
+// instead of requiring every host to be listed statically in the Supfile.
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultTimeout is how long a provider is given to collect answers before
+// its results are considered final.
+const DefaultTimeout = 2 * time.Second
+
+// Provider discovers hosts reachable on the local network and returns them
+// as dial-able "host:port" strings.
+type Provider interface {
+	// Name identifies the provider in warnings/logs, e.g. "mdns" or "broadcast".
+	Name() string
+	// Discover browses for hosts for up to cfg.Timeout and returns whatever
+	// it found. It must not treat "nothing found" as an error.
+	Discover(cfg Config) ([]string, error)
+}
+
+// Config configures a single discovery provider run.
+type Config struct {
+	// Namespace scopes discovery to a single sup fleet, so unrelated sup
+	// users on the same LAN don't see each other's hosts.
+	Namespace string
+	// ServiceType is the mDNS/DNS-SD service to browse, e.g. "_sup._tcp.local.".
+	// Ignored by providers that don't use DNS-SD naming (e.g. broadcast).
+	ServiceType string
+	// Port is the UDP port used by the broadcast provider, and the port
+	// advertised/assumed for hosts found by either provider when the
+	// response doesn't carry its own port.
+	Port int
+	// Timeout bounds how long a provider may spend collecting answers.
+	// Defaults to DefaultTimeout when zero.
+	Timeout time.Duration
+}
+
+func (cfg Config) timeout() time.Duration {
+	if cfg.Timeout <= 0 {
+		return DefaultTimeout
+	}
+
+	return cfg.Timeout
+}
+
+// Discover runs every provider in turn, merges their results (deduped), and
+// returns the combined host list. A provider that fails to discover any
+// host within its timeout only produces a warning, never a fatal error;
+// provider errors are returned as warnings too, and Discover itself never
+// fails.
+func Discover(cfg Config, providers ...Provider) ([]string, []error) {
+	seen := make(map[string]bool)
+
+	var (
+		hosts    []string
+		warnings []error
+	)
+
+	for _, p := range providers {
+		found, err := p.Discover(cfg)
+		if err != nil {
+			warnings = append(warnings, fmt.Errorf("discovery(%s): %w", p.Name(), err))
+			continue
+		}
+
+		if len(found) == 0 {
+			warnings = append(warnings, fmt.Errorf("discovery(%s): %w", p.Name(), errNoHostsFound))
+			continue
+		}
+
+		for _, host := range found {
+			if seen[host] {
+				continue
+			}
+
+			seen[host] = true
+
+			hosts = append(hosts, host)
+		}
+	}
+
+	return hosts, warnings
+}
+
+var errNoHostsFound = errors.New("no hosts discovered within timeout")
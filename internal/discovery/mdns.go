@@ -0,0 +1,200 @@
+package discovery
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// mdnsAddr is the IPv4 mDNS multicast group and port, as defined by RFC 6762.
+var mdnsAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// MDNS discovers hosts by browsing a DNS-SD service type over mDNS
+// (RFC 6762/6763), e.g. "_sup._tcp.local.". It only depends on the stdlib
+// and golang.org/x/net, so it works without an external mDNS daemon.
+type MDNS struct{}
+
+func (MDNS) Name() string { return "mdns" }
+
+func (m MDNS) Discover(cfg Config) ([]string, error) {
+	serviceType := cfg.ServiceType
+	if serviceType == "" {
+		serviceType = "_sup._tcp.local."
+	}
+
+	// ListenMulticastUDP, not ListenUDP: responses arrive addressed to the
+	// 224.0.0.251 multicast group, and a plain unicast socket never joins
+	// that group, so the kernel drops them before we ever see them.
+	conn, err := net.ListenMulticastUDP("udp4", nil, mdnsAddr)
+	if err != nil {
+		return nil, errors.Join(err, errors.New("opening mDNS socket failed"))
+	}
+	defer conn.Close()
+
+	query, err := buildQuery(serviceType)
+	if err != nil {
+		return nil, errors.Join(err, errors.New("building mDNS query failed"))
+	}
+
+	if _, err := conn.WriteToUDP(query, mdnsAddr); err != nil {
+		return nil, errors.Join(err, errors.New("sending mDNS query failed"))
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(cfg.timeout())); err != nil {
+		return nil, errors.Join(err, errors.New("setting mDNS read deadline failed"))
+	}
+
+	var hosts []string
+
+	buf := make([]byte, 8192)
+
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			// Read timeout ends the collection window; that's expected.
+			break
+		}
+
+		host, ok := parseAnswer(buf[:n], cfg.Namespace, cfg.Port)
+		if !ok {
+			continue
+		}
+
+		hosts = append(hosts, host)
+	}
+
+	return hosts, nil
+}
+
+func buildQuery(serviceType string) ([]byte, error) {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{})
+
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+
+	name, err := dnsmessage.NewName(serviceType)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := b.Question(dnsmessage.Question{
+		Name:  name,
+		Type:  dnsmessage.TypePTR,
+		Class: dnsmessage.ClassINET,
+	}); err != nil {
+		return nil, err
+	}
+
+	return b.Finish()
+}
+
+// parseAnswer pulls a host:port string out of a single mDNS response packet.
+// When namespace is set, only responses with a TXT record carrying
+// "ns=<namespace>" are accepted, so multiple sup fleets on the same LAN
+// don't cross-talk; responses without such a record are ignored.
+//
+// Compliant DNS-SD responders (avahi, Bonjour, ...) put the SRV/TXT/A
+// records that let us resolve in one round trip in the Additional section,
+// not the Answer section (RFC 6763 §12.1), so both are scanned.
+func parseAnswer(packet []byte, namespace string, defaultPort int) (string, bool) {
+	var parser dnsmessage.Parser
+
+	if _, err := parser.Start(packet); err != nil {
+		return "", false
+	}
+
+	if err := parser.SkipAllQuestions(); err != nil {
+		return "", false
+	}
+
+	var (
+		host     string
+		port     = defaultPort
+		gotTXTNS = namespace == ""
+	)
+
+	for {
+		header, err := parser.AnswerHeader()
+		if err != nil {
+			break
+		}
+
+		if err := applyAnswerRecord(&parser, header, namespace, &host, &port, &gotTXTNS, parser.SkipAnswer); err != nil {
+			return "", false
+		}
+	}
+
+	if err := parser.SkipAllAuthorities(); err != nil {
+		return "", false
+	}
+
+	for {
+		header, err := parser.AdditionalHeader()
+		if err != nil {
+			break
+		}
+
+		if err := applyAnswerRecord(&parser, header, namespace, &host, &port, &gotTXTNS, parser.SkipAdditional); err != nil {
+			return "", false
+		}
+	}
+
+	if host == "" || !gotTXTNS {
+		return "", false
+	}
+
+	if port == 0 {
+		return host, true
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(port)), true
+}
+
+// applyAnswerRecord decodes a single resource record, already positioned by
+// an AnswerHeader/AdditionalHeader call, into the host/port/namespace state
+// shared across both sections. skip is the section-specific Skip* method,
+// used for record types we don't care about.
+func applyAnswerRecord(parser *dnsmessage.Parser, header dnsmessage.ResourceHeader, namespace string, host *string, port *int, gotTXTNS *bool, skip func() error) error {
+	switch header.Type {
+	case dnsmessage.TypeSRV:
+		srv, err := parser.SRVResource()
+		if err != nil {
+			return err
+		}
+
+		*host = strings.TrimSuffix(srv.Target.String(), ".")
+		*port = int(srv.Port)
+
+	case dnsmessage.TypeA:
+		a, err := parser.AResource()
+		if err != nil {
+			return err
+		}
+
+		if *host == "" {
+			*host = net.IP(a.A[:]).String()
+		}
+
+	case dnsmessage.TypeTXT:
+		txt, err := parser.TXTResource()
+		if err != nil {
+			return err
+		}
+
+		for _, kv := range txt.TXT {
+			if strings.EqualFold(kv, "ns="+namespace) {
+				*gotTXTNS = true
+			}
+		}
+
+	default:
+		return skip()
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package discovery
+
+import (
+	"net"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// buildDNSSDResponse builds a minimal DNS-SD response carrying an A and a
+// TXT record for host, placed in the Answer section or the Additional
+// section depending on inAdditional.
+func buildDNSSDResponse(t *testing.T, inAdditional bool, host string, ns string) []byte {
+	t.Helper()
+
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true})
+
+	if err := b.StartQuestions(); err != nil {
+		t.Fatalf("StartQuestions: %v", err)
+	}
+
+	if inAdditional {
+		if err := b.StartAdditionals(); err != nil {
+			t.Fatalf("StartAdditionals: %v", err)
+		}
+	} else {
+		if err := b.StartAnswers(); err != nil {
+			t.Fatalf("StartAnswers: %v", err)
+		}
+	}
+
+	name, err := dnsmessage.NewName("host.local.")
+	if err != nil {
+		t.Fatalf("NewName: %v", err)
+	}
+
+	hdr := dnsmessage.ResourceHeader{Name: name, Class: dnsmessage.ClassINET}
+
+	var addr [4]byte
+	copy(addr[:], net.ParseIP(host).To4())
+
+	aHdr := hdr
+	aHdr.Type = dnsmessage.TypeA
+
+	if err := b.AResource(aHdr, dnsmessage.AResource{A: addr}); err != nil {
+		t.Fatalf("AResource: %v", err)
+	}
+
+	txtHdr := hdr
+	txtHdr.Type = dnsmessage.TypeTXT
+
+	if err := b.TXTResource(txtHdr, dnsmessage.TXTResource{TXT: []string{"ns=" + ns}}); err != nil {
+		t.Fatalf("TXTResource: %v", err)
+	}
+
+	packet, err := b.Finish()
+	if err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+
+	return packet
+}
+
+func TestParseAnswer(t *testing.T) {
+	tests := []struct {
+		name         string
+		inAdditional bool
+		packetNS     string
+		namespace    string
+		wantOK       bool
+	}{
+		{
+			name:      "records in Answer section",
+			namespace: "prod",
+			packetNS:  "prod",
+			wantOK:    true,
+		},
+		{
+			name:         "records in Additional section, as real DNS-SD responders send them",
+			inAdditional: true,
+			namespace:    "prod",
+			packetNS:     "prod",
+			wantOK:       true,
+		},
+		{
+			name:         "namespace mismatch is rejected",
+			inAdditional: true,
+			namespace:    "prod",
+			packetNS:     "staging",
+			wantOK:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			packet := buildDNSSDResponse(t, tt.inAdditional, "192.0.2.10", tt.packetNS)
+
+			host, ok := parseAnswer(packet, tt.namespace, 2222)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+
+			if !tt.wantOK {
+				return
+			}
+
+			const want = "192.0.2.10:2222"
+			if host != want {
+				t.Fatalf("host = %q, want %q", host, want)
+			}
+		})
+	}
+}
+
+func TestParseAnswerMalformedPacket(t *testing.T) {
+	if _, ok := parseAnswer([]byte("not a dns message"), "prod", 0); ok {
+		t.Fatal("expected malformed packet to be rejected")
+	}
+}
@@ -0,0 +1,192 @@
+// Package log is a small leveled, structured logging facade for sup. It
+// replaces the scattered fmt.Fprintf(os.Stderr, ...) calls that used to
+// carry diagnostics and task lifecycle events, so both humans (text) and
+// log aggregators (json) can consume a sup run.
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a log event, lowest first so level filtering is
+// a plain numeric comparison.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseLevel parses the --log-level flag value, defaulting to info on an
+// empty string.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(s) {
+	case "", "info":
+		return LevelInfo, nil
+	case "debug":
+		return LevelDebug, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return LevelInfo, fmt.Errorf("unknown log level %q", s)
+	}
+}
+
+// Format is the rendering used for each log event.
+type Format int
+
+const (
+	Text Format = iota
+	JSON
+)
+
+// ParseFormat parses the --log-format flag value, defaulting to text on an
+// empty string.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "", "text":
+		return Text, nil
+	case "json":
+		return JSON, nil
+	default:
+		return Text, fmt.Errorf("unknown log format %q", s)
+	}
+}
+
+// Field is one structured key/value attached to a log event. The key names
+// match across call sites so aggregators can index on them regardless of
+// which part of sup produced the event.
+type Field struct {
+	Key   string
+	Value any
+}
+
+func Host(v string) Field              { return Field{"host", v} }
+func Command(v string) Field           { return Field{"command", v} }
+func Task(v string) Field              { return Field{"task", v} }
+func ExitCode(v int) Field             { return Field{"exit_code", v} }
+func DurationMS(v time.Duration) Field { return Field{"duration_ms", v.Milliseconds()} }
+func Line(v string) Field              { return Field{"line", v} }
+func Err(err error) Field              { return Field{"error", err.Error()} }
+
+// Logger writes leveled, structured log events as either plain text or one
+// JSON object per line. It is safe for concurrent use, since clients report
+// from their own goroutines.
+type Logger struct {
+	mu     sync.Mutex
+	out    io.Writer
+	level  Level
+	format Format
+}
+
+// New builds a Logger writing to out, filtering out events below level.
+func New(out io.Writer, level Level, format Format) *Logger {
+	return &Logger{out: out, level: level, format: format}
+}
+
+// SetLevel adjusts the minimum level logged, e.g. to drop to LevelDebug
+// once --debug is passed.
+func (l *Logger) SetLevel(level Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.level = level
+}
+
+// IsJSON reports whether the logger renders one JSON object per event,
+// which callers can use to decide whether to emit per-line stdout/stderr
+// events instead of streaming raw task output.
+func (l *Logger) IsJSON() bool {
+	return l.format == JSON
+}
+
+func (l *Logger) Debug(event string, fields ...Field) { l.log(LevelDebug, event, fields...) }
+func (l *Logger) Info(event string, fields ...Field)  { l.log(LevelInfo, event, fields...) }
+func (l *Logger) Warn(event string, fields ...Field)  { l.log(LevelWarn, event, fields...) }
+func (l *Logger) Error(event string, fields ...Field) { l.log(LevelError, event, fields...) }
+
+func (l *Logger) log(level Level, event string, fields ...Field) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if level < l.level {
+		return
+	}
+
+	if l.format == JSON {
+		l.writeJSON(level, event, fields)
+		return
+	}
+
+	l.writeText(level, event, fields)
+}
+
+func (l *Logger) writeJSON(level Level, event string, fields []Field) {
+	entry := make(map[string]any, len(fields)+3)
+	entry["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	entry["level"] = level.String()
+	entry["event"] = event
+
+	for _, f := range fields {
+		entry[f.Key] = f.Value
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		fmt.Fprintf(l.out, "marshaling log entry failed: %v\n", err)
+		return
+	}
+
+	l.out.Write(append(data, '\n'))
+}
+
+// writeText renders the event as "host | level event key=val ...", keeping
+// the host leading the line the same way the old "user@host |" task output
+// prefix did (uncolored here: color is cosmetic and stays with the task
+// output prefixer in Stackup.runTask).
+func (l *Logger) writeText(level Level, event string, fields []Field) {
+	var b strings.Builder
+
+	for _, f := range fields {
+		if f.Key == "host" {
+			fmt.Fprintf(&b, "%v | ", f.Value)
+			break
+		}
+	}
+
+	fmt.Fprintf(&b, "%-5s %s", level, event)
+
+	for _, f := range fields {
+		if f.Key == "host" {
+			continue
+		}
+
+		fmt.Fprintf(&b, " %s=%v", f.Key, f.Value)
+	}
+
+	fmt.Fprintln(l.out, b.String())
+}
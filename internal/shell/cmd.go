@@ -0,0 +1,28 @@
+package shell
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/DTreshy/sup/internal/envs"
+)
+
+// cmdExe runs scripts through cmd.exe via `/C <script>`.
+type cmdExe struct{}
+
+func (cmdExe) Name() string { return "cmd" }
+
+func (cmdExe) Export(env envs.EnvList) string {
+	var b strings.Builder
+
+	for _, e := range env {
+		fmt.Fprintf(&b, "set %s=%s&&", e.Key, e.Value)
+	}
+
+	return b.String()
+}
+
+func (cmdExe) Command(script string) *exec.Cmd {
+	return exec.Command("cmd.exe", "/C", script)
+}
@@ -0,0 +1,28 @@
+package shell
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/DTreshy/sup/internal/envs"
+)
+
+// posixShell runs scripts through bash, sh or zsh via `<bin> -c <script>`.
+type posixShell struct{ bin string }
+
+func (s posixShell) Name() string { return s.bin }
+
+func (s posixShell) Export(env envs.EnvList) string {
+	var b strings.Builder
+
+	for _, e := range env {
+		fmt.Fprintf(&b, "export %s=%q; ", e.Key, e.Value)
+	}
+
+	return b.String()
+}
+
+func (s posixShell) Command(script string) *exec.Cmd {
+	return exec.Command(s.bin, "-c", script)
+}
@@ -0,0 +1,53 @@
+package shell
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/DTreshy/sup/internal/envs"
+)
+
+// powershell runs scripts through PowerShell Core (pwsh) or Windows
+// PowerShell (powershell.exe) via `-Command <script>`.
+type powershell struct{ bin string }
+
+func (s powershell) Name() string { return "pwsh" }
+
+func (s powershell) Export(env envs.EnvList) string {
+	var b strings.Builder
+
+	for _, e := range env {
+		fmt.Fprintf(&b, "$env:%s = %s; ", e.Key, psQuote(e.Value))
+	}
+
+	return b.String()
+}
+
+// psQuote renders s as a PowerShell double-quoted string literal. Go's %q
+// escapes backslash, which PowerShell doesn't treat as an escape character
+// inside "...", so a Windows path like `C:\Users\foo` would come out
+// doubled; instead only backtick, `$` and `"` need a backtick escape, since
+// those are what PowerShell treats specially in a double-quoted string.
+func psQuote(s string) string {
+	var b strings.Builder
+
+	b.WriteByte('"')
+
+	for _, r := range s {
+		switch r {
+		case '`', '$', '"':
+			b.WriteByte('`')
+		}
+
+		b.WriteRune(r)
+	}
+
+	b.WriteByte('"')
+
+	return b.String()
+}
+
+func (s powershell) Command(script string) *exec.Cmd {
+	return exec.Command(s.bin, "-NoProfile", "-NonInteractive", "-Command", script)
+}
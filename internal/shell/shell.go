@@ -0,0 +1,87 @@
+// Package shell abstracts over the command interpreter LocalhostClient runs
+// scripts through, so sup behaves the same whether it's invoked on Linux,
+// macOS or Windows, and regardless of which shell happens to be installed.
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"github.com/DTreshy/sup/internal/envs"
+)
+
+// Shell knows how to render an env preamble, quote a script, and build the
+// exec.Cmd that runs it as a one-shot command.
+type Shell interface {
+	// Name identifies the shell, matching the `shell:`/SUP_SHELL value.
+	Name() string
+	// Export renders env as an assignment preamble in this shell's syntax,
+	// e.g. `export FOO="bar"; ` or `$env:FOO = "bar"; `.
+	Export(env envs.EnvList) string
+	// Command builds the exec.Cmd that runs script as a one-shot command.
+	Command(script string) *exec.Cmd
+}
+
+// Recognized shell names, used for the `shell:` Supfile key and SUP_SHELL.
+const (
+	Bash       = "bash"
+	Sh         = "sh"
+	Zsh        = "zsh"
+	PowerShell = "pwsh"
+	Cmd        = "cmd"
+)
+
+// Get resolves name to a Shell implementation. An empty or unrecognized
+// name falls back to Detect.
+func Get(name string) Shell {
+	if sh, ok := lookup(name); ok {
+		return sh
+	}
+
+	return Detect()
+}
+
+// Detect picks a Shell from $SUP_SHELL; failing that, it picks PowerShell on
+// Windows, and elsewhere $SHELL, falling back to "sh".
+func Detect() Shell {
+	if sh, ok := lookup(os.Getenv("SUP_SHELL")); ok {
+		return sh
+	}
+
+	if runtime.GOOS == "windows" {
+		return powershell{bin: powershellBin()}
+	}
+
+	if sh, ok := lookup(filepath.Base(os.Getenv("SHELL"))); ok {
+		return sh
+	}
+
+	return posixShell{bin: "sh"}
+}
+
+func lookup(name string) (Shell, bool) {
+	switch name {
+	case Bash:
+		return posixShell{bin: "bash"}, true
+	case Sh:
+		return posixShell{bin: "sh"}, true
+	case Zsh:
+		return posixShell{bin: "zsh"}, true
+	case PowerShell, "powershell", "powershell.exe":
+		return powershell{bin: powershellBin()}, true
+	case Cmd, "cmd.exe":
+		return cmdExe{}, true
+	default:
+		return nil, false
+	}
+}
+
+func powershellBin() string {
+	if _, err := exec.LookPath("pwsh"); err == nil {
+		return "pwsh"
+	}
+
+	return "powershell.exe"
+}
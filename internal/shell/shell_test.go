@@ -0,0 +1,84 @@
+package shell
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/DTreshy/sup/internal/envs"
+)
+
+// envValues covers plain text alongside the two shapes that have broken
+// quoting in one shell backend or another: a Windows path (embedded
+// backslashes) and a value carrying a double quote.
+var envValues = []string{"hello", `C:\Users\foo`, `say "hi"`}
+
+func TestShellEnvPropagation(t *testing.T) {
+	for _, name := range []string{Bash, Sh, Zsh, PowerShell, Cmd} {
+		name := name
+
+		t.Run(name, func(t *testing.T) {
+			sh := Get(name)
+
+			if _, err := exec.LookPath(sh.Command(echoScript(name)).Args[0]); err != nil {
+				t.Skipf("%s not installed: %v", name, err)
+			}
+
+			for _, value := range envValues {
+				value := value
+
+				t.Run(value, func(t *testing.T) {
+					preamble := sh.Export(envs.EnvList{{Key: "SUP_TEST", Value: value}})
+					cmd := sh.Command(preamble + echoScript(name))
+
+					out, err := cmd.Output()
+					if err != nil {
+						t.Fatalf("running script: %v", err)
+					}
+
+					got := strings.TrimRight(string(out), "\r\n")
+					if got != value {
+						t.Fatalf("env var not propagated, got %q, want %q", got, value)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestShellExitCodeCapture(t *testing.T) {
+	for _, name := range []string{Bash, Sh, Zsh, PowerShell, Cmd} {
+		name := name
+
+		t.Run(name, func(t *testing.T) {
+			sh := Get(name)
+			cmd := sh.Command("exit 3")
+
+			if _, err := exec.LookPath(cmd.Args[0]); err != nil {
+				t.Skipf("%s not installed: %v", cmd.Args[0], err)
+			}
+
+			err := cmd.Run()
+
+			exitErr, ok := err.(*exec.ExitError)
+			if !ok {
+				t.Fatalf("expected *exec.ExitError, got %T (%v)", err, err)
+			}
+
+			if exitErr.ExitCode() != 3 {
+				t.Fatalf("expected exit code 3, got %d", exitErr.ExitCode())
+			}
+		})
+	}
+}
+
+func echoScript(name string) string {
+	switch name {
+	case Cmd:
+		return "echo %SUP_TEST%"
+	case PowerShell:
+		return "Write-Output $env:SUP_TEST"
+	default:
+		return `printf '%s\n' "$SUP_TEST"`
+	}
+}
@@ -0,0 +1,88 @@
+package sup
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// bastionChain dials hosts through an ordered chain of jump hosts (à la
+// OpenSSH's `ProxyJump host1,host2,host3`), caching the *SSHClient opened
+// for each hop by the chain prefix leading to it. A 50-host fleet behind
+// the same two jump hosts therefore opens those jump connections once, not
+// fifty times.
+type bastionChain struct {
+	mu    sync.Mutex
+	cache map[string]*SSHClient
+}
+
+func newBastionChain() *bastionChain {
+	return &bastionChain{cache: make(map[string]*SSHClient)}
+}
+
+// dialer returns the dial function to reach a host behind the given
+// ordered chain of jump hosts, connecting to (and caching) whichever
+// prefix of the chain isn't already open. An empty chain dials directly.
+func (bc *bastionChain) dialer(chain []string) (dialFunc, error) {
+	if len(chain) == 0 {
+		return net.Dial, nil
+	}
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	dial := dialFunc(net.Dial)
+	key := ""
+
+	for _, hop := range chain {
+		if key != "" {
+			key += ","
+		}
+
+		key += hop
+
+		if client, ok := bc.cache[key]; ok {
+			dial = client.DialThrough
+			continue
+		}
+
+		client := &SSHClient{}
+		if err := client.ConnectWith(hop, dial); err != nil {
+			return nil, errors.Join(err, fmt.Errorf("connecting to bastion %v failed", hop))
+		}
+
+		bc.cache[key] = client
+		dial = client.DialThrough
+	}
+
+	return dial, nil
+}
+
+// close tears down every bastion connection opened by dialer.
+func (bc *bastionChain) close() {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	for _, client := range bc.cache {
+		client.Close()
+	}
+}
+
+// parseHostVia splits a "user@host:port via a,b" host entry into the host
+// proper and its per-host jump chain, layered on top of the network's own
+// bastion chain.
+func parseHostVia(host string) (string, []string) {
+	parts := strings.SplitN(host, " via ", 2)
+	if len(parts) != 2 {
+		return host, nil
+	}
+
+	hops := strings.Split(parts[1], ",")
+	for i, hop := range hops {
+		hops[i] = strings.TrimSpace(hop)
+	}
+
+	return strings.TrimSpace(parts[0]), hops
+}
@@ -0,0 +1,78 @@
+package sup
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/DTreshy/sup/internal/command"
+)
+
+// ErrCommandNoRun is returned when a command has no script to run.
+var ErrCommandNoRun = errors.New("command has no run script")
+
+// Client is implemented by LocalhostClient and SSHClient, the two transports
+// a Task can be run over. ctx is threaded through Run so a client can be
+// torn down forcibly (SIGHUP/SIGTERM grace window expiring) without racing
+// its own in-flight I/O.
+type Client interface {
+	Connect(host string) error
+	Run(ctx context.Context, task *Task) error
+	Wait() error
+	Close() error
+	Stdin() io.WriteCloser
+	Stdout() io.Reader
+	Stderr() io.Reader
+	WriteClose() error
+	Signal(sig os.Signal) error
+	Prefix() (prefix string, prefixLen int)
+	// Host returns the plain (uncolored) host name/address this client is
+	// connected to, for structured logging.
+	Host() string
+}
+
+// Task is a single command to be run on a set of clients. Each Client
+// prepends its own env preamble (see LocalhostClient.env/SSHClient.env)
+// when it actually runs the task.
+type Task struct {
+	Run     string
+	Input   io.Reader
+	Clients []Client
+	// TTY requests a remote/local pseudo-terminal for this task, so
+	// interactive programs (vim, mysql, sudo prompts, ...) work as expected.
+	TTY bool
+	// Shell overrides the client's shell for this task only, e.g. a
+	// command's `shell:` key. Empty keeps the client's own choice.
+	Shell string
+}
+
+// ErrTask wraps an error that occurred while starting or running a task on
+// a Client, keeping the task around so callers can report it with context.
+type ErrTask struct {
+	Task *Task
+	Err  string
+}
+
+func (e ErrTask) Error() string {
+	return e.Err
+}
+
+// createTasks translates a command into the task(s) to run on the given
+// clients. The env preamble is added per-client at Run time, not here (see
+// LocalhostClient.env/SSHClient.env), since it can be rendered differently
+// per shell.
+func (sup *Stackup) createTasks(cmd *command.Command, clients []Client) ([]*Task, error) {
+	run := strings.TrimSpace(cmd.Run)
+	if run == "" {
+		return nil, ErrCommandNoRun
+	}
+
+	return []*Task{{
+		Run:     run,
+		Clients: clients,
+		TTY:     cmd.TTY || sup.tty,
+		Shell:   cmd.Shell,
+	}}, nil
+}
@@ -0,0 +1,75 @@
+package sup
+
+import (
+	"errors"
+	"os/exec"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// RunErrors aggregates every error observed while waiting for clients to
+// finish a Run, instead of the first one calling os.Exit out from under the
+// others. It implements Unwrap() []error so callers can still errors.Is/As
+// into it, and tracks the highest remote exit status observed so main can
+// choose a faithful process exit code.
+type RunErrors struct {
+	mu       sync.Mutex
+	errs     []error
+	ExitCode int
+}
+
+// Add records err, guarded by a mutex since clients report from their own
+// goroutines. It is a no-op when err is nil.
+func (e *RunErrors) Add(err error) {
+	if err == nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.errs = append(e.errs, err)
+
+	if code, ok := exitStatus(err); ok && code > e.ExitCode {
+		e.ExitCode = code
+	}
+}
+
+// Empty reports whether no error was ever added.
+func (e *RunErrors) Empty() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return len(e.errs) == 0
+}
+
+func (e *RunErrors) Error() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return errors.Join(e.errs...).Error()
+}
+
+// Unwrap lets errors.Is/As see through to every collected error.
+func (e *RunErrors) Unwrap() []error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.errs
+}
+
+// exitStatus extracts a remote/local process exit status from err, if any.
+func exitStatus(err error) (int, bool) {
+	var sshExitErr *ssh.ExitError
+	if errors.As(err, &sshExitErr) {
+		return sshExitErr.ExitStatus(), true
+	}
+
+	var execExitErr *exec.ExitError
+	if errors.As(err, &execExitErr) {
+		return execExitErr.ExitCode(), true
+	}
+
+	return 0, false
+}
@@ -1,12 +1,17 @@
 package sup
 
 import (
+	"context"
 	"errors"
 	"io"
 	"os"
 	"os/exec"
 	"os/user"
 
+	"github.com/creack/pty"
+
+	"github.com/DTreshy/sup/internal/envs"
+	"github.com/DTreshy/sup/internal/shell"
 	"github.com/DTreshy/sup/pkg/colors"
 )
 
@@ -18,7 +23,12 @@ type LocalhostClient struct {
 	stdout  io.Reader
 	stderr  io.Reader
 	running bool
-	env     string //export FOO="bar"; export BAR="baz";
+	env     envs.EnvList
+	shell   string // network's shell: key, empty means auto-detect (shell.Detect)
+	ptmx    *os.File
+	// done is closed once the command finishes normally, so the ctx-watcher
+	// goroutine started by Run doesn't kill a process that's already gone.
+	done chan struct{}
 }
 
 func (c *LocalhostClient) Connect(_ string) error {
@@ -32,20 +42,37 @@ func (c *LocalhostClient) Connect(_ string) error {
 	return nil
 }
 
-func (c *LocalhostClient) Run(task *Task) error {
+func (c *LocalhostClient) Run(ctx context.Context, task *Task) error {
 	var err error
 
 	if c.running {
 		return errors.New("Command already running")
 	}
 
-	cmdArgs := []string{
-		"-c",
-		c.env + task.Run,
+	sh := shell.Get(c.shell)
+	if task.Shell != "" {
+		sh = shell.Get(task.Shell)
 	}
-	cmd := exec.Command("bash", cmdArgs...)
+
+	cmd := sh.Command(sh.Export(c.env) + task.Run)
 	c.cmd = cmd
 
+	if task.TTY {
+		ptmx, err := pty.Start(cmd)
+		if err != nil {
+			return ErrTask{task, err.Error()}
+		}
+
+		c.ptmx = ptmx
+		c.stdin = ptmx
+		c.stdout = ptmx
+		c.stderr = ptmx
+		c.running = true
+		c.watch(ctx)
+
+		return nil
+	}
+
 	c.stdout, err = cmd.StdoutPipe()
 	if err != nil {
 		return err
@@ -66,10 +93,37 @@ func (c *LocalhostClient) Run(task *Task) error {
 	}
 
 	c.running = true
+	c.watch(ctx)
 
 	return nil
 }
 
+// watch kills the running process if ctx is cancelled before the command
+// finishes on its own.
+func (c *LocalhostClient) watch(ctx context.Context) {
+	c.done = make(chan struct{})
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			if c.cmd.Process != nil {
+				c.cmd.Process.Kill()
+			}
+		case <-c.done:
+		}
+	}()
+}
+
+// Resize forwards a local window size change to the pseudo-terminal running
+// the local command.
+func (c *LocalhostClient) Resize(rows, cols uint16) error {
+	if c.ptmx == nil {
+		return nil
+	}
+
+	return pty.Setsize(c.ptmx, &pty.Winsize{Rows: rows, Cols: cols})
+}
+
 func (c *LocalhostClient) Wait() error {
 	if !c.running {
 		return errors.New("trying to wait on stopped command")
@@ -78,10 +132,16 @@ func (c *LocalhostClient) Wait() error {
 	err := c.cmd.Wait()
 	c.running = false
 
+	close(c.done)
+
 	return err
 }
 
 func (c *LocalhostClient) Close() error {
+	if c.ptmx != nil {
+		return c.ptmx.Close()
+	}
+
 	return nil
 }
 
@@ -97,6 +157,12 @@ func (c *LocalhostClient) Stdout() io.Reader {
 	return c.stdout
 }
 
+// Host always reports "localhost", since that's what every LocalhostClient
+// is connected to.
+func (c *LocalhostClient) Host() string {
+	return "localhost"
+}
+
 func (c *LocalhostClient) Prefix() (prefix string, prefixLen int) {
 	host := c.user + "@localhost" + " | "
 	return colors.ResetColor + host, len(host)
@@ -111,16 +177,20 @@ func (c *LocalhostClient) WriteClose() error {
 }
 
 func (c *LocalhostClient) Signal(sig os.Signal) error {
+	if !c.running {
+		return errors.New("signaling: command not running")
+	}
+
 	return c.cmd.Process.Signal(sig)
 }
 
-func ResolveLocalPath(cwd, path, env string) (string, error) {
-	// Check if file exists first. Use bash to resolve $ENV_VARs.
-	resolveEnvVarsArgs := []string{
-		"-c",
-		env + "echo -n " + path,
-	}
-	cmd := exec.Command("bash", resolveEnvVarsArgs...)
+// ResolveLocalPath resolves env vars (e.g. "~" or "$HOME") in path by asking
+// shellName's interpreter to expand it, so it works the same way the shell
+// running the task would expand it. An empty shellName auto-detects.
+func ResolveLocalPath(cwd, path string, env envs.EnvList, shellName string) (string, error) {
+	sh := shell.Get(shellName)
+
+	cmd := sh.Command(sh.Export(env) + echoPath(sh, path))
 	cmd.Dir = cwd
 
 	resolvedFilename, err := cmd.Output()
@@ -130,3 +200,15 @@ func ResolveLocalPath(cwd, path, env string) (string, error) {
 
 	return string(resolvedFilename), nil
 }
+
+// echoPath renders a no-newline echo of path in sh's syntax.
+func echoPath(sh shell.Shell, path string) string {
+	switch sh.Name() {
+	case "cmd":
+		return "echo|set /p=" + path
+	case "pwsh":
+		return "Write-Host -NoNewline " + path
+	default:
+		return "echo -n " + path
+	}
+}
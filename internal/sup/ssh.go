@@ -0,0 +1,265 @@
+package sup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/term"
+)
+
+// SSHClient is a wrapper over an SSH connection/session pair.
+type SSHClient struct {
+	conn    *ssh.Client
+	sess    *ssh.Session
+	stdin   io.WriteCloser
+	stdout  io.Reader
+	stderr  io.Reader
+	running bool
+	env     string //export FOO="bar"; export BAR="baz";
+	user    string
+	host    string
+	color   string
+	// done is closed once the task's session finishes normally, so the
+	// ctx-watcher goroutine started by Run doesn't force-close a session
+	// that's already gone.
+	done chan struct{}
+}
+
+// dialFunc matches net.Dial's signature, and is what lets a client be
+// connected either directly or through another client's tunnel.
+type dialFunc func(network, addr string) (net.Conn, error)
+
+func (c *SSHClient) Connect(host string) error {
+	return c.ConnectWith(host, net.Dial)
+}
+
+// ConnectWith connects to the given host using dial to establish the
+// underlying connection, so the caller can route through a bastion (see
+// DialThrough) instead of dialing directly.
+func (c *SSHClient) ConnectWith(host string, dial dialFunc) error {
+	if c.conn != nil {
+		return errors.New("already connected")
+	}
+
+	c.host = host
+
+	user := c.user
+	if user == "" {
+		user = os.Getenv("USER")
+	}
+
+	config := &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{sshAgentAuth()},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}
+
+	addr := host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "22")
+	}
+
+	netConn, err := dial("tcp", addr)
+	if err != nil {
+		return errors.Join(err, fmt.Errorf("dialing %v failed", addr))
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(netConn, addr, config)
+	if err != nil {
+		return errors.Join(err, fmt.Errorf("establishing SSH connection to %v failed", addr))
+	}
+
+	c.conn = ssh.NewClient(sshConn, chans, reqs)
+
+	return nil
+}
+
+// DialThrough dials addr through this client's own SSH connection, so it can
+// be used as the dial function for a host behind this client acting as a
+// bastion/jump host.
+func (c *SSHClient) DialThrough(network, addr string) (net.Conn, error) {
+	return c.conn.Dial(network, addr)
+}
+
+func (c *SSHClient) Run(ctx context.Context, task *Task) error {
+	if c.running {
+		return errors.New("command already running")
+	}
+
+	sess, err := c.conn.NewSession()
+	if err != nil {
+		return errors.Join(err, errors.New("opening SSH session failed"))
+	}
+
+	c.sess = sess
+
+	stdin, err := c.sess.StdinPipe()
+	if err != nil {
+		return err
+	}
+	c.stdin = stdin
+
+	stdout, err := c.sess.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	c.stdout = stdout
+
+	stderr, err := c.sess.StderrPipe()
+	if err != nil {
+		return err
+	}
+	c.stderr = stderr
+
+	if task.TTY {
+		if err := c.requestPty(); err != nil {
+			return errors.Join(err, errors.New("requesting PTY failed"))
+		}
+	}
+
+	if err := c.sess.Start(c.env + task.Run); err != nil {
+		return ErrTask{task, err.Error()}
+	}
+
+	c.running = true
+	c.done = make(chan struct{})
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.sess.Close()
+		case <-c.done:
+		}
+	}()
+
+	return nil
+}
+
+// requestPty asks the remote for a PTY sized to the local terminal, using
+// the local TERM so cursor-addressed programs render correctly.
+func (c *SSHClient) requestPty() error {
+	termEnv := os.Getenv("TERM")
+	if termEnv == "" {
+		termEnv = "xterm-256color"
+	}
+
+	width, height, err := term.GetSize(int(os.Stdin.Fd()))
+	if err != nil {
+		width, height = 80, 24
+	}
+
+	modes := ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.TTY_OP_ISPEED: 14400,
+		ssh.TTY_OP_OSPEED: 14400,
+	}
+
+	return c.sess.RequestPty(termEnv, height, width, modes)
+}
+
+// Resize forwards a local window size change to the remote PTY.
+func (c *SSHClient) Resize(rows, cols uint16) error {
+	if c.sess == nil {
+		return nil
+	}
+
+	return c.sess.WindowChange(int(rows), int(cols))
+}
+
+func (c *SSHClient) Wait() error {
+	if !c.running {
+		return errors.New("trying to wait on stopped command")
+	}
+
+	err := c.sess.Wait()
+	c.running = false
+
+	close(c.done)
+
+	return err
+}
+
+func (c *SSHClient) Close() error {
+	if c.sess != nil {
+		c.sess.Close()
+	}
+
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+
+	return nil
+}
+
+func (c *SSHClient) Stdin() io.WriteCloser {
+	return c.stdin
+}
+
+func (c *SSHClient) Stdout() io.Reader {
+	return c.stdout
+}
+
+func (c *SSHClient) Stderr() io.Reader {
+	return c.stderr
+}
+
+func (c *SSHClient) WriteClose() error {
+	return c.Stdin().Close()
+}
+
+func (c *SSHClient) Signal(sig os.Signal) error {
+	if !c.running {
+		return errors.New("signaling: command not running")
+	}
+
+	s, ok := sshSignals[sig]
+	if !ok {
+		return fmt.Errorf("signal %v not supported over SSH", sig)
+	}
+
+	return c.sess.Signal(s)
+}
+
+// Host returns the plain (uncolored) host this client is connected to.
+func (c *SSHClient) Host() string {
+	return c.host
+}
+
+func (c *SSHClient) Prefix() (prefix string, prefixLen int) {
+	user := c.user
+	if user == "" {
+		user = os.Getenv("USER")
+	}
+
+	host := user + "@" + c.host + " | "
+
+	return c.color + host, len(host)
+}
+
+func sshAgentAuth() ssh.AuthMethod {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return ssh.PublicKeysCallback(func() ([]ssh.Signer, error) { return nil, nil })
+	}
+
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		return ssh.PublicKeysCallback(func() ([]ssh.Signer, error) { return nil, nil })
+	}
+
+	return ssh.PublicKeysCallback(agent.NewClient(conn).Signers)
+}
+
+var sshSignals = map[os.Signal]ssh.Signal{
+	os.Interrupt:    ssh.SIGINT,
+	syscall.SIGHUP:  ssh.SIGHUP,
+	syscall.SIGTERM: ssh.SIGTERM,
+	syscall.SIGQUIT: ssh.SIGQUIT,
+}
@@ -1,19 +1,23 @@
 package sup
 
 import (
+	"bufio"
+	"context"
 	"errors"
-	"fmt"
 	"io"
 	"os"
 	"os/signal"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/goware/prefixer"
-	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
 
 	"github.com/DTreshy/sup/internal/command"
 	"github.com/DTreshy/sup/internal/envs"
+	"github.com/DTreshy/sup/internal/log"
 	"github.com/DTreshy/sup/internal/network"
 	"github.com/DTreshy/sup/internal/supfile"
 	"github.com/DTreshy/sup/pkg/colors"
@@ -21,15 +25,35 @@ import (
 
 const VERSION = "0.5"
 
+// defaultGrace is how long a client is given to exit on its own after the
+// first signal before it is forced closed; a second signal forces it closed
+// at once.
+const defaultGrace = 5 * time.Second
+
+// ErrTTYMultipleHosts is returned when --tty/tty: true is requested against
+// more than one host, since the hosts would otherwise fight over the same
+// local terminal. Pass --tty-force to override.
+var ErrTTYMultipleHosts = errors.New("refusing to allocate a TTY for multiple hosts, pass --tty-force to override")
+
 type Stackup struct {
-	conf   *supfile.Supfile
-	debug  bool
-	prefix bool
+	conf     *supfile.Supfile
+	logger   *log.Logger
+	prefix   bool
+	tty      bool
+	ttyForce bool
+	grace    time.Duration
+}
+
+// resizer is implemented by clients that can forward a terminal window size
+// change to the process they're running.
+type resizer interface {
+	Resize(rows, cols uint16) error
 }
 
 func New(conf *supfile.Supfile) (*Stackup, error) {
 	return &Stackup{
-		conf: conf,
+		conf:   conf,
+		logger: log.New(os.Stderr, log.LevelInfo, log.Text),
 	}, nil
 }
 
@@ -44,16 +68,16 @@ func (sup *Stackup) Run(net *network.Network, envVars envs.EnvList, commands ...
 
 	env := envVars.AsExport()
 
-	// Create clients for every host (either SSH or Localhost).
-	var bastion *SSHClient
-
-	if net.Bastion != "" {
-		bastion = &SSHClient{}
-		if err := bastion.Connect(net.Bastion); err != nil {
-			return errors.Join(err, errors.New("connecting to bastion failed"))
-		}
+	// Network-level bastion chain, e.g. `bastions: [a, b, c]`, falling back
+	// to the legacy single `bastion: a` key.
+	networkChain := net.Bastions
+	if len(networkChain) == 0 && net.Bastion != "" {
+		networkChain = []string{net.Bastion}
 	}
 
+	bastions := newBastionChain()
+	defer bastions.close()
+
 	var wg sync.WaitGroup
 
 	clientCh := make(chan Client, len(net.Hosts))
@@ -65,39 +89,59 @@ func (sup *Stackup) Run(net *network.Network, envVars envs.EnvList, commands ...
 		go func(i int, host string) {
 			defer wg.Done()
 
+			// Per-host "user@host:port via a,b" jump chain, layered on top
+			// of the network's own bastion chain.
+			realHost, viaHops := parseHostVia(host)
+
+			connectStart := time.Now()
+
 			// Localhost client.
-			if host == "localhost" {
+			if realHost == "localhost" {
+				hostEnv := make(envs.EnvList, len(envVars), len(envVars)+1)
+				copy(hostEnv, envVars)
+				hostEnv = append(hostEnv, envs.Env{Key: "SUP_HOST", Value: realHost})
+
 				local := &LocalhostClient{
-					env: env + `export SUP_HOST="` + host + `";`,
+					env:   hostEnv,
+					shell: net.Shell,
 				}
-				if err := local.Connect(host); err != nil {
+				if err := local.Connect(realHost); err != nil {
+					sup.logger.Error("connect", log.Host(realHost), log.Err(err))
 					errCh <- errors.Join(err, errors.New("connecting to localhost failed"))
 					return
 				}
 
+				sup.logger.Info("connect", log.Host(realHost), log.DurationMS(time.Since(connectStart)))
 				clientCh <- local
 
 				return
 			}
 
+			chain := make([]string, 0, len(networkChain)+len(viaHops))
+			chain = append(chain, networkChain...)
+			chain = append(chain, viaHops...)
+
+			dial, err := bastions.dialer(chain)
+			if err != nil {
+				sup.logger.Error("connect", log.Host(realHost), log.Err(err))
+				errCh <- err
+				return
+			}
+
 			// SSH client.
 			remote := &SSHClient{
-				env:   env + `export SUP_HOST="` + host + `";`,
+				env:   env + `export SUP_HOST="` + realHost + `";`,
 				user:  net.User,
 				color: colors.Colors[i%len(colors.Colors)],
 			}
 
-			if bastion != nil {
-				if err := remote.ConnectWith(host, bastion.DialThrough); err != nil {
-					errCh <- errors.Join(err, errors.New("connecting to remote host through bastion failed"))
-					return
-				}
-			} else {
-				if err := remote.Connect(host); err != nil {
-					errCh <- errors.Join(err, errors.New("connecting to remote host failed"))
-					return
-				}
+			if err := remote.ConnectWith(realHost, dial); err != nil {
+				sup.logger.Error("connect", log.Host(realHost), log.Err(err))
+				errCh <- errors.Join(err, errors.New("connecting to remote host failed"))
+				return
 			}
+
+			sup.logger.Info("connect", log.Host(realHost), log.DurationMS(time.Since(connectStart)))
 			clientCh <- remote
 		}(i, host)
 	}
@@ -125,153 +169,276 @@ func (sup *Stackup) Run(net *network.Network, envVars envs.EnvList, commands ...
 		return errors.Join(err, errors.New("connecting to clients failed"))
 	}
 
+	anyTTY := sup.tty
+	for _, cmd := range commands {
+		if cmd.TTY {
+			anyTTY = true
+			break
+		}
+	}
+
+	if anyTTY && len(clients) > 1 && !sup.ttyForce {
+		return ErrTTYMultipleHosts
+	}
+
+	// ctx is cancelled once the run is draining and the grace window has
+	// expired, forcing every client closed instead of waiting forever for
+	// a graceful exit.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runErrs := &RunErrors{}
+
+	trap := make(chan os.Signal, 1)
+	signal.Notify(trap, os.Interrupt, syscall.SIGHUP, syscall.SIGTERM, syscall.SIGQUIT)
+
+	defer signal.Stop(trap)
+	defer close(trap)
+
+	go sup.handleSignals(trap, clients, cancel)
+
 	// Run command or run multiple commands defined by target sequentially.
 	for _, cmd := range commands {
 		// Translate command into task(s).
-		tasks, err := sup.createTasks(cmd, clients, env)
+		tasks, err := sup.createTasks(cmd, clients)
 		if err != nil {
 			return errors.Join(err, errors.New("creating task failed"))
 		}
 
 		// Run tasks sequentially.
 		for _, task := range tasks {
-			var (
-				writers []io.Writer
-				wg      sync.WaitGroup
-			)
-
-			// Run tasks on the provided clients.
-			for _, c := range task.Clients {
-				var (
-					prefix    string
-					prefixLen int
-				)
-
-				if sup.prefix {
-					prefix, prefixLen = c.Prefix()
-					if len(prefix) < maxLen { // Left padding.
-						prefix = strings.Repeat(" ", maxLen-prefixLen) + prefix
-					}
-				}
+			if err := sup.runTask(ctx, cmd, task, clients, maxLen, runErrs); err != nil {
+				return err
+			}
+		}
+	}
 
-				err := c.Run(task)
-				if err != nil {
-					return errors.Join(err, errors.New(prefix+"task failed"))
-				}
+	if !runErrs.Empty() {
+		return runErrs
+	}
 
-				// Copy over tasks's STDOUT.
-				wg.Add(1)
+	return nil
+}
 
-				go func(c Client) {
-					defer wg.Done()
+// runTask runs a single task on its clients, wiring STDIN/STDOUT/STDERR and
+// waiting for every client to finish. It's split out from Run so that the
+// TTY raw-mode restore, entered below, can be a defer scoped to one task
+// instead of a call at the end of the happy path — a client failing to
+// start (e.g. a remote RequestPty error) must still leave the local
+// terminal the way it found it.
+func (sup *Stackup) runTask(ctx context.Context, cmd *command.Command, task *Task, clients []Client, maxLen int, runErrs *RunErrors) error {
+	var (
+		writers []io.Writer
+		wg      sync.WaitGroup
+	)
+
+	// TTY mode puts the local terminal in raw mode and wires it straight
+	// into the task, instead of whatever Input the task already carries,
+	// so cursor-addressed programs work.
+	if task.TTY {
+		fd := int(os.Stdin.Fd())
+
+		oldState, err := term.MakeRaw(fd)
+		if err != nil {
+			return errors.Join(err, errors.New("putting local terminal into raw mode failed"))
+		}
 
-					_, err := io.Copy(os.Stdout, prefixer.New(c.Stdout(), prefix))
-					if err != nil && err != io.EOF {
-						// TODO: io.Copy() should not return io.EOF at all.
-						// Upstream bug? Or prefixer.WriteTo() bug?
-						fmt.Fprintf(os.Stderr, "%v", errors.Join(err, errors.New(prefix+"reading STDOUT failed")))
-					}
-				}(c)
+		defer term.Restore(fd, oldState)
 
-				// Copy over tasks's STDERR.
-				wg.Add(1)
+		task.Input = os.Stdin
+	}
 
-				go func(c Client) {
-					defer wg.Done()
+	runStart := time.Now()
+
+	// Run tasks on the provided clients.
+	for _, c := range task.Clients {
+		var (
+			prefix    string
+			prefixLen int
+		)
+
+		// Prefixing corrupts cursor-addressed output, so it's
+		// disabled whenever a TTY was requested.
+		if sup.prefix && !task.TTY {
+			prefix, prefixLen = c.Prefix()
+			if len(prefix) < maxLen { // Left padding.
+				prefix = strings.Repeat(" ", maxLen-prefixLen) + prefix
+			}
+		}
 
-					_, err := io.Copy(os.Stderr, prefixer.New(c.Stderr(), prefix))
-					if err != nil && err != io.EOF {
-						fmt.Fprintf(os.Stderr, "%v", errors.Join(err, errors.New(prefix+"reading STDERR failed")))
-					}
-				}(c)
+		err := c.Run(ctx, task)
+		if err != nil {
+			return errors.Join(err, errors.New(prefix+"task failed"))
+		}
+
+		sup.logger.Info("start", log.Host(c.Host()), log.Command(cmd.Name), log.Task(task.Run))
+
+		// Copy over tasks's STDOUT.
+		wg.Add(1)
+
+		go func(c Client) {
+			defer wg.Done()
 
-				writers = append(writers, c.Stdin())
+			if sup.logger.IsJSON() {
+				sup.logLines(c.Host(), cmd.Name, "stdout-line", c.Stdout())
+				return
 			}
 
-			// Copy over task's STDIN.
-			if task.Input != nil {
-				go func() {
-					writer := io.MultiWriter(writers...)
+			_, err := io.Copy(os.Stdout, prefixer.New(c.Stdout(), prefix))
+			if err != nil && err != io.EOF {
+				// TODO: io.Copy() should not return io.EOF at all.
+				// Upstream bug? Or prefixer.WriteTo() bug?
+				sup.logger.Error("reading STDOUT failed", log.Host(c.Host()), log.Err(err))
+			}
+		}(c)
 
-					_, err := io.Copy(writer, task.Input)
-					if err != nil && err != io.EOF {
-						fmt.Fprintf(os.Stderr, "%v", errors.Join(err, errors.New("copying STDIN failed")))
-					}
-					// TODO: Use MultiWriteCloser (not in Stdlib), so we can writer.Close() instead?
-					for _, c := range clients {
-						c.WriteClose()
-					}
-				}()
+		// Copy over tasks's STDERR.
+		wg.Add(1)
+
+		go func(c Client) {
+			defer wg.Done()
+
+			if sup.logger.IsJSON() {
+				sup.logLines(c.Host(), cmd.Name, "stderr-line", c.Stderr())
+				return
 			}
 
-			// Catch OS signals and pass them to all active clients.
-			trap := make(chan os.Signal, 1)
+			_, err := io.Copy(os.Stderr, prefixer.New(c.Stderr(), prefix))
+			if err != nil && err != io.EOF {
+				sup.logger.Error("reading STDERR failed", log.Host(c.Host()), log.Err(err))
+			}
+		}(c)
 
-			signal.Notify(trap, os.Interrupt)
+		writers = append(writers, c.Stdin())
+	}
+
+	// Copy over task's STDIN.
+	if task.Input != nil {
+		go func() {
+			writer := io.MultiWriter(writers...)
 
-			go func() {
-				for {
-					sig, ok := <-trap
+			_, err := io.Copy(writer, task.Input)
+			if err != nil && err != io.EOF {
+				sup.logger.Error("copying STDIN failed", log.Err(err))
+			}
+			// TODO: Use MultiWriteCloser (not in Stdlib), so we can writer.Close() instead?
+			for _, c := range clients {
+				c.WriteClose()
+			}
+		}()
+	}
+
+	// In TTY mode, forward local window size changes to every
+	// active client so full-screen programs redraw correctly.
+	winch := make(chan os.Signal, 1)
+
+	if task.TTY {
+		signal.Notify(winch, syscall.SIGWINCH)
+
+		go func() {
+			for range winch {
+				cols, rows, err := term.GetSize(int(os.Stdin.Fd()))
+				if err != nil {
+					continue
+				}
+
+				for _, c := range task.Clients {
+					r, ok := c.(resizer)
 					if !ok {
-						return
+						continue
 					}
 
-					for _, c := range task.Clients {
-						err := c.Signal(sig)
-						if err != nil {
-							fmt.Fprintf(os.Stderr, "%v", errors.Join(err, errors.New("sending signal failed")))
-						}
+					if err := r.Resize(uint16(rows), uint16(cols)); err != nil {
+						sup.logger.Error("forwarding window size failed", log.Host(c.Host()), log.Err(err))
 					}
 				}
-			}()
+			}
+		}()
+	}
+
+	// Wait for all I/O operations first.
+	wg.Wait()
+
+	// Make sure each client finishes the task, collecting failures in runErrs.
+	for _, c := range task.Clients {
+		wg.Add(1)
 
-			// Wait for all I/O operations first.
-			wg.Wait()
+		go func(c Client) {
+			defer wg.Done()
 
-			// Make sure each client finishes the task, return on failure.
-			for _, c := range task.Clients {
-				wg.Add(1)
+			err := c.Wait()
 
-				go func(c Client) {
-					defer wg.Done()
+			fields := []log.Field{log.Host(c.Host()), log.Command(cmd.Name), log.DurationMS(time.Since(runStart))}
+			if err == nil {
+				sup.logger.Info("exit", fields...)
+				return
+			}
 
-					if err := c.Wait(); err != nil {
-						var prefix string
+			if code, ok := exitStatus(err); ok {
+				fields = append(fields, log.ExitCode(code))
+			}
 
-						if sup.prefix {
-							var prefixLen int
+			sup.logger.Error("exit", append(fields, log.Err(err))...)
 
-							prefix, prefixLen = c.Prefix()
+			runErrs.Add(err)
+		}(c)
+	}
 
-							if len(prefix) < maxLen { // Left padding.
-								prefix = strings.Repeat(" ", maxLen-prefixLen) + prefix
-							}
-						}
+	// Wait for all commands to finish.
+	wg.Wait()
 
-						if e, ok := err.(*ssh.ExitError); ok && e.ExitStatus() != 15 {
-							// TODO: Store all the errors, and print them after Wait().
-							fmt.Fprintf(os.Stderr, "%s%v\n", prefix, e)
-							os.Exit(e.ExitStatus())
-						}
+	if task.TTY {
+		signal.Stop(winch)
+		close(winch)
+	}
 
-						fmt.Fprintf(os.Stderr, "%s%v\n", prefix, err)
+	return nil
+}
 
-						// TODO: Shouldn't os.Exit(1) here. Instead, collect the exit statuses for later.
-						os.Exit(1)
-					}
-				}(c)
+// handleSignals forwards the first trapped signal to every client and
+// starts the grace window; a second signal, or the grace window expiring,
+// cancels ctx so clients tear down forcibly instead of waiting forever for
+// a graceful exit. This replaces trapping only os.Interrupt per-task, and
+// fixes the SIGHUP-vs-disconnect race that occurs when sup itself is run
+// under `ssh host -- sup ...` and the parent OpenSSH sends SIGHUP
+// concurrently with EOF on the channel.
+func (sup *Stackup) handleSignals(trap <-chan os.Signal, clients []Client, cancel context.CancelFunc) {
+	draining := false
+
+	for sig := range trap {
+		for _, c := range clients {
+			if err := c.Signal(sig); err != nil {
+				sup.logger.Error("forwarding signal failed", log.Host(c.Host()), log.Err(err))
 			}
+		}
+
+		if draining {
+			cancel()
+			continue
+		}
 
-			// Wait for all commands to finish.
-			wg.Wait()
+		draining = true
 
-			// Stop catching signals for the currently active clients.
-			signal.Stop(trap)
-			close(trap)
+		grace := sup.grace
+		if grace <= 0 {
+			grace = defaultGrace
 		}
+
+		go func() {
+			time.Sleep(grace)
+			cancel()
+		}()
 	}
+}
 
-	return nil
+// logLines reads r line by line and emits each as its own log event, so a
+// --log-format=json run can be piped into a log aggregator instead of
+// producing interleaved, prefixed task output.
+func (sup *Stackup) logLines(host, cmdName, event string, r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		sup.logger.Info(event, log.Host(host), log.Command(cmdName), log.Line(scanner.Text()))
+	}
 }
 
 func closeRemotes(clients []Client) {
@@ -282,10 +449,39 @@ func closeRemotes(clients []Client) {
 	}
 }
 
+// Debug drops the logger's minimum level to LevelDebug when value is true;
+// false leaves the level unchanged.
 func (sup *Stackup) Debug(value bool) {
-	sup.debug = value
+	if value {
+		sup.logger.SetLevel(log.LevelDebug)
+	}
+}
+
+// Logger replaces the default (info level, text format, stderr) logger,
+// e.g. to honor --log-level/--log-format.
+func (sup *Stackup) Logger(logger *log.Logger) {
+	sup.logger = logger
 }
 
 func (sup *Stackup) Prefix(value bool) {
 	sup.prefix = value
 }
+
+// TTY enables PTY allocation, either because a command set `tty: true` or
+// the user passed -t/--tty.
+func (sup *Stackup) TTY(value bool) {
+	sup.tty = value
+}
+
+// TTYForce allows TTY mode against more than one host, bypassing the
+// refusal in Run.
+func (sup *Stackup) TTYForce(value bool) {
+	sup.ttyForce = value
+}
+
+// Grace sets how long a client is given to exit gracefully after the first
+// signal before it is forced closed; a second signal forces it closed at
+// once. Zero keeps the default (defaultGrace).
+func (sup *Stackup) Grace(d time.Duration) {
+	sup.grace = d
+}